@@ -2,17 +2,18 @@ package chilog
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // RequestIDHeader is the name of the HTTP Header which contains the request id.
@@ -20,15 +21,20 @@ import (
 var RequestIDHeader = "X-Request-Id"
 
 type logFields struct {
-	RemoteIP   string
-	Host       string
-	Method     string
-	Path       string
-	Body       string
-	StatusCode int
-	Latency    float64
-	Error      error
-	Stack      []byte
+	RemoteIP      string
+	Host          string
+	Method        string
+	Path          string
+	Query         string
+	Headers       map[string]string
+	Body          string
+	ContentLength int64
+	StatusCode    int
+	RespBody      string
+	Latency       float64
+	Slow          bool
+	Error         error
+	Frames        []Frame
 }
 
 func (l *logFields) MarshalZerologObject(e *zerolog.Event) {
@@ -37,22 +43,49 @@ func (l *logFields) MarshalZerologObject(e *zerolog.Event) {
 		Str("host", l.Host).
 		Str("method", l.Method).
 		Str("path", l.Path).
-		Str("body", l.Body).
+		Int64("content_length", l.ContentLength).
 		Int("status_code", l.StatusCode).
 		Float64("latency", l.Latency).
 		Str("tag", "request")
 
+	if l.Slow {
+		e.Bool("slow", true)
+	}
+
+	if l.Query != "" {
+		e.Str("query", l.Query)
+	}
+
+	if l.Headers != nil {
+		e.Interface("headers", l.Headers)
+	}
+
+	if l.Body != "" {
+		e.Str("body", l.Body)
+	}
+
+	if l.RespBody != "" {
+		e.Str("resp_body", l.RespBody)
+	}
+
 	if l.Error != nil {
 		e.Err(l.Error)
 	}
 
-	if l.Stack != nil {
-		e.Bytes("stack", l.Stack)
+	if len(l.Frames) > 0 {
+		arr := zerolog.Arr()
+		for _, f := range l.Frames {
+			arr.Dict(zerolog.Dict().Str("func", f.Func).Str("file", f.File).Int("line", f.Line))
+		}
+		e.Array("stack", arr)
 	}
 }
 
-// Middleware contains functionality of request_id, logger and recover for request traceability
-func Middleware(filter func(w http.ResponseWriter, r *http.Request) bool) func(http.Handler) http.Handler {
+// Middleware contains functionality of request_id, logger and recover for request traceability.
+// opts controls body capture limits, redaction, and which headers/query params are logged; the
+// zero value is usable and logs the request body whenever its content type is in
+// DefaultLoggableContentTypes.
+func Middleware(opts Options, filter func(w http.ResponseWriter, r *http.Request) bool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Check filter
@@ -70,31 +103,63 @@ func Middleware(filter func(w http.ResponseWriter, r *http.Request) bool) func(h
 				r.Header.Set(RequestIDHeader, uuid.New().String())
 			}
 
-			ctx := log.With().
+			// Parse the inbound traceparent/tracestate (or B3) headers so logs
+			// from this hop can be joined with the rest of the trace, generating
+			// a fresh trace ID when the request arrived with none.
+			tr := extractTrace(r)
+			traceID, spanID := tr.TraceID, tr.SpanID
+
+			ctx := r.Context()
+
+			var span trace.Span
+			if opts.Tracer != nil {
+				spanCtx := ctx
+				if tr.Remote.IsValid() {
+					spanCtx = trace.ContextWithRemoteSpanContext(spanCtx, tr.Remote)
+				}
+
+				ctx, span = opts.Tracer.Start(spanCtx, r.Method+" "+r.URL.Path, trace.WithSpanKind(trace.SpanKindServer))
+				defer span.End()
+
+				sc := span.SpanContext()
+				traceID, spanID = sc.TraceID().String(), sc.SpanID().String()
+			}
+
+			// Derive the logger once, after the span (if any) has started, so
+			// trace_id/span_id are set exactly once from their final values.
+			ctx = log.With().
 				Str("request_id", r.Header.Get(RequestIDHeader)).
+				Str("trace_id", traceID).
+				Str("span_id", spanID).
 				Logger().
-				WithContext(r.Context())
+				WithContext(ctx)
 
 			// Wraps an http.ResponseWriter, returning a proxy that allows you to
 			// hook into various parts of the response process.
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
-			// Read request body
-			var buf []byte
-			if r.Body != nil {
-				buf, _ = io.ReadAll(r.Body)
-
-				// Restore the io.ReadCloser to its original state
-				r.Body = io.NopCloser(bytes.NewBuffer(buf))
+			bw := &bufferedResponseWriter{WrapResponseWriter: ww}
+			if opts.LogResponseBody && opts.maxBodyBytes() > 0 {
+				bw.buf = new(bytes.Buffer)
+				bw.max = opts.maxBodyBytes()
 			}
 
 			// Create log fields
 			fields := &logFields{
-				RemoteIP: r.RemoteAddr,
-				Method:   r.Method,
-				Host:     r.Host,
-				Path:     r.URL.Path,
-				Body:     formatReqBody(buf),
+				RemoteIP:      r.RemoteAddr,
+				Method:        r.Method,
+				Host:          r.Host,
+				Path:          r.URL.Path,
+				ContentLength: r.ContentLength,
+				Headers:       redactHeaders(r.Header, opts.LogHeaders, opts.RedactHeaders),
+			}
+
+			if opts.LogQuery {
+				fields.Query = redactQuery(r.URL.Query(), opts.RedactQueryParams)
+			}
+
+			if !opts.DisableRequestBody {
+				fields.Body = captureRequestBody(r, opts)
 			}
 
 			defer func() {
@@ -113,13 +178,49 @@ func Middleware(filter func(w http.ResponseWriter, r *http.Request) bool) func(h
 					}
 
 					fields.Error = err
-					fields.Stack = debug.Stack()
+					fields.Frames = collectTrace(opts.TraceSkip, opts.maxTraceback())
 
-					w.WriteHeader(http.StatusInternalServerError)
+					if opts.PanicHandler != nil {
+						opts.PanicHandler(ctx, err, fields.Frames)
+					}
+
+					if span != nil {
+						span.RecordError(err)
+						span.SetStatus(codes.Error, err.Error())
+					}
+
+					writeError(opts, bw, r, err)
 				}
 
+				elapsed := time.Since(start)
 				fields.StatusCode = ww.Status()
-				fields.Latency = float64(time.Since(start).Nanoseconds()/1e4) / 100.0
+				fields.Latency = float64(elapsed.Nanoseconds()/1e4) / 100.0
+
+				slow := opts.SlowRequestThreshold > 0 && elapsed >= opts.SlowRequestThreshold
+				fields.Slow = slow
+
+				if span != nil {
+					span.SetAttributes(httpStatusAttribute(fields.StatusCode))
+					if rvr == nil && fields.StatusCode >= 500 {
+						span.SetStatus(codes.Error, http.StatusText(fields.StatusCode))
+					}
+				}
+
+				sampled := true
+				if rvr == nil && !slow && opts.Sampler != nil {
+					sampled = opts.Sampler.Sample(r, fields.StatusCode, elapsed)
+				}
+
+				if !slow && !sampled {
+					return
+				}
+
+				if bw.buf != nil && isLoggableContentType(ww.Header().Get("Content-Type"), opts.loggableContentTypes()) {
+					fields.RespBody = formatBody(bw.buf.Bytes(), opts.redactKeys(), opts.maxBodyBytes())
+					if bw.truncated && !strings.HasSuffix(fields.RespBody, truncatedMarker) {
+						fields.RespBody += truncatedMarker
+					}
+				}
 
 				switch {
 				case rvr != nil:
@@ -130,6 +231,8 @@ func Middleware(filter func(w http.ResponseWriter, r *http.Request) bool) func(h
 					log.Ctx(ctx).Error().EmbedObject(fields).Msg("client error")
 				case fields.StatusCode >= 300:
 					log.Ctx(ctx).Warn().EmbedObject(fields).Msg("redirect")
+				case slow:
+					log.Ctx(ctx).Warn().EmbedObject(fields).Msg("slow request")
 				case fields.StatusCode >= 200:
 					log.Ctx(ctx).Info().EmbedObject(fields).Msg("success")
 				case fields.StatusCode >= 100:
@@ -140,23 +243,90 @@ func Middleware(filter func(w http.ResponseWriter, r *http.Request) bool) func(h
 
 			}()
 
-			next.ServeHTTP(ww, r.WithContext(ctx))
+			next.ServeHTTP(bw, r.WithContext(ctx))
 
 		})
 	}
 }
 
-func formatReqBody(data []byte) string {
-	var js map[string]interface{}
-	if json.Unmarshal(data, &js) != nil {
-		return string(data)
+// captureRequestBody reads at most max+1 bytes off r.Body for logging, then
+// restores r.Body so downstream handlers still see the full payload, without
+// ever buffering more than that in memory ourselves regardless of how large
+// the real body is. Bodies whose content type is not in opts' loggable list
+// are left uncaptured; only ContentLength is logged for those.
+func captureRequestBody(r *http.Request, opts Options) string {
+	if r.Body == nil {
+		return ""
+	}
+
+	max := opts.maxBodyBytes()
+	if max <= 0 {
+		return ""
 	}
 
-	result := new(bytes.Buffer)
-	if err := json.Compact(result, data); err != nil {
-		log.Error().Err(err).Msg("error compacting body request json")
+	if !isLoggableContentType(r.Header.Get("Content-Type"), opts.loggableContentTypes()) {
 		return ""
 	}
 
-	return result.String()
+	buf, _ := io.ReadAll(io.LimitReader(r.Body, max+1))
+	r.Body = restoreBody(buf, r.Body)
+
+	if len(buf) == 0 {
+		return ""
+	}
+
+	truncated := int64(len(buf)) > max
+	if truncated {
+		buf = buf[:max]
+	}
+
+	body := formatBody(buf, opts.redactKeys(), max)
+	if truncated && !strings.HasSuffix(body, truncatedMarker) {
+		body += truncatedMarker
+	}
+	return body
+}
+
+// restoreBody rebuilds a ReadCloser equivalent to the original r.Body: the
+// bytes already captured followed by whatever orig (now advanced past them)
+// has left to give. This lets captureRequestBody cap how much it buffers
+// without truncating the body the handler actually receives.
+func restoreBody(captured []byte, orig io.ReadCloser) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.MultiReader(bytes.NewReader(captured), orig),
+		Closer: orig,
+	}
+}
+
+// bufferedResponseWriter tees the response body into buf, up to max bytes,
+// so it can be logged after the handler returns. buf is nil unless response
+// body logging is enabled; while it is nil, Write is a plain passthrough.
+// truncated records whether more was written than buf ended up holding, so
+// the caller can mark the logged copy as a partial one.
+type bufferedResponseWriter struct {
+	middleware.WrapResponseWriter
+	buf       *bytes.Buffer
+	max       int64
+	truncated bool
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	if w.buf != nil {
+		if int64(w.buf.Len()) < w.max {
+			remaining := w.max - int64(w.buf.Len())
+			if int64(len(b)) < remaining {
+				remaining = int64(len(b))
+			}
+			w.buf.Write(b[:remaining])
+			if remaining < int64(len(b)) {
+				w.truncated = true
+			}
+		} else if len(b) > 0 {
+			w.truncated = true
+		}
+	}
+	return w.WrapResponseWriter.Write(b)
 }
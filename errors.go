@@ -0,0 +1,117 @@
+package chilog
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// EndpointFunc is shaped like an http.Handler but returns an error instead of
+// writing the response itself. Wrap one with Handler to get a standard
+// http.HandlerFunc that reports the returned error through WriteError.
+type EndpointFunc func(w http.ResponseWriter, r *http.Request) error
+
+// Handler adapts fn into an http.HandlerFunc, writing any error it returns
+// via WriteError under opts. w is wrapped in a status-tracking writer unless
+// it already is one (e.g. when Handler runs under chilog.Middleware), so the
+// "don't write a second response" check in writeError works whether Handler
+// is used standalone or nested.
+func Handler(opts Options, fn EndpointFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ww := w
+		if _, ok := w.(statusGetter); !ok {
+			ww = middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		}
+
+		if err := fn(ww, r); err != nil {
+			writeError(opts, ww, r, err)
+		}
+	}
+}
+
+// HTTPError is an error that carries the HTTP status and a machine-readable
+// code/message pair, meant to be returned from an EndpointFunc or passed to
+// WriteError. Cause, if set, is included in Error() and returned by Unwrap,
+// but is never written to clients.
+type HTTPError struct {
+	Status  int
+	Code    string
+	Message string
+	Details interface{}
+	Cause   error
+}
+
+func (e *HTTPError) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Cause
+}
+
+// problemDetails is the application/problem+json body written by WriteError,
+// following the shape described in RFC 7807.
+type problemDetails struct {
+	Status    int         `json:"status"`
+	Error     string      `json:"error"`
+	Message   string      `json:"message"`
+	Code      string      `json:"code,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// statusGetter is implemented by middleware.WrapResponseWriter and lets
+// WriteError tell whether a response has already been started.
+type statusGetter interface {
+	Status() int
+}
+
+// WriteError writes err as an application/problem+json body tagged with the
+// request's X-Request-Id, so clients can correlate with server logs. It
+// unwraps *HTTPError for status/code/message/details; any other error is
+// reported as a generic 500. If w has already had WriteHeader called on it,
+// WriteError does nothing, since the handler has taken responsibility for
+// the response. This is detected only when w has a Status() method, as
+// chi's middleware.WrapResponseWriter does.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(Options{}, w, r, err)
+}
+
+func writeError(opts Options, w http.ResponseWriter, r *http.Request, err error) {
+	if sg, ok := w.(statusGetter); ok && sg.Status() != 0 {
+		return
+	}
+
+	if opts.ErrorWriter != nil {
+		opts.ErrorWriter(w, r, err)
+		return
+	}
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		httpErr = &HTTPError{Status: http.StatusInternalServerError, Message: "internal server error", Cause: err}
+	}
+
+	status := httpErr.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	body := problemDetails{
+		Status:    status,
+		Error:     http.StatusText(status),
+		Message:   httpErr.Message,
+		Code:      httpErr.Code,
+		Details:   httpErr.Details,
+		RequestID: r.Header.Get(RequestIDHeader),
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
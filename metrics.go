@@ -0,0 +1,139 @@
+package chilog
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsOptions configures the Prometheus collectors registered by Metrics.
+type MetricsOptions struct {
+	// Namespace is prefixed to every metric name, e.g. "myapp" produces
+	// "myapp_http_requests_total".
+	Namespace string
+
+	// DurationBuckets overrides the histogram buckets used for
+	// http_request_duration_seconds. Nil uses prometheus.DefBuckets.
+	DurationBuckets []float64
+}
+
+type metricsCollectors struct {
+	requestsTotal   *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	requestSize     *prometheus.HistogramVec
+	responseSize    *prometheus.HistogramVec
+	inFlight        prometheus.Gauge
+}
+
+func newMetricsCollectors(reg prometheus.Registerer, opts MetricsOptions) *metricsCollectors {
+	buckets := opts.DurationBuckets
+	if buckets == nil {
+		buckets = prometheus.DefBuckets
+	}
+
+	c := &metricsCollectors{
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.Namespace,
+			Name:      "http_requests_total",
+			Help:      "Total number of HTTP requests processed.",
+		}, []string{"method", "route", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opts.Namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency in seconds.",
+			Buckets:   buckets,
+		}, []string{"method", "route", "code"}),
+		requestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opts.Namespace,
+			Name:      "http_request_size_bytes",
+			Help:      "HTTP request body size in bytes.",
+			Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+		}, []string{"method", "route"}),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opts.Namespace,
+			Name:      "http_response_size_bytes",
+			Help:      "HTTP response body size in bytes.",
+			Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+		}, []string{"method", "route", "code"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: opts.Namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "Number of HTTP requests currently being served.",
+		}),
+	}
+
+	reg.MustRegister(c.requestsTotal, c.requestDuration, c.requestSize, c.responseSize, c.inFlight)
+	return c
+}
+
+// Metrics returns middleware that records Prometheus metrics for every
+// request, reusing the same response writer wrapping as Middleware. Route
+// cardinality is kept bounded by labeling with the matched chi route
+// pattern instead of the raw URL path.
+//
+// Recording happens in a deferred, recovering func so a panicking handler
+// still gets counted (as a 500) before the panic continues unwinding to an
+// outer recover, e.g. Middleware's, which writes the actual response.
+func Metrics(reg prometheus.Registerer, opts MetricsOptions) func(http.Handler) http.Handler {
+	c := newMetricsCollectors(reg, opts)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			c.inFlight.Inc()
+			defer c.inFlight.Dec()
+
+			defer func() {
+				rvr := recover()
+
+				status := ww.Status()
+				if rvr != nil && status == 0 {
+					status = http.StatusInternalServerError
+				}
+
+				route := routePattern(r)
+				code := strconv.Itoa(status)
+
+				c.requestsTotal.WithLabelValues(r.Method, route, code).Inc()
+				c.requestDuration.WithLabelValues(r.Method, route, code).Observe(time.Since(start).Seconds())
+				c.requestSize.WithLabelValues(r.Method, route).Observe(float64(r.ContentLength))
+				c.responseSize.WithLabelValues(r.Method, route, code).Observe(float64(ww.BytesWritten()))
+
+				if rvr != nil {
+					panic(rvr)
+				}
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
+
+// routePattern returns the matched chi route pattern for r, falling back to
+// "unmatched" when chi hasn't matched a registered route, e.g. for requests
+// that 404 before reaching one.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if p := rctx.RoutePattern(); p != "" {
+			return p
+		}
+	}
+	return "unmatched"
+}
+
+// Default returns the ordered middleware chain used across chilog-based
+// services: Middleware for traceable logging followed by Metrics for
+// Prometheus instrumentation, e.g. r.Use(chilog.Default(reg, opts, metricsOpts, nil)...).
+func Default(reg prometheus.Registerer, opts Options, metricsOpts MetricsOptions, filter func(w http.ResponseWriter, r *http.Request) bool) []func(http.Handler) http.Handler {
+	return []func(http.Handler) http.Handler{
+		Middleware(opts, filter),
+		Metrics(reg, metricsOpts),
+	}
+}
@@ -0,0 +1,143 @@
+package chilog
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultMaxBodyBytes is the cap applied to captured request/response bodies
+// when Options.MaxBodyBytes is left at zero.
+const DefaultMaxBodyBytes int64 = 64 * 1024
+
+// truncatedMarker is appended to a captured body that was cut off at the
+// configured byte cap, so readers can tell a short body from a truncated one.
+const truncatedMarker = "...(truncated)"
+
+// DefaultRedactedKeys lists the JSON object keys that are redacted from
+// logged bodies when Options.RedactKeys is nil.
+var DefaultRedactedKeys = []string{"password", "token", "secret", "access_token", "refresh_token", "authorization"}
+
+// DefaultLoggableContentTypes lists the content types, matched by prefix,
+// whose bodies are safe to capture as text when Options.LoggableContentTypes
+// is nil. Anything else (multipart uploads, octet-stream, images, ...) is
+// skipped and only the content length is logged.
+var DefaultLoggableContentTypes = []string{
+	"application/json",
+	"text/plain",
+	"text/xml",
+	"application/xml",
+	"application/x-www-form-urlencoded",
+}
+
+// Options configures body capture, redaction, and header/query logging for
+// Middleware. The zero value is usable: it captures the request body, up to
+// DefaultMaxBodyBytes, whenever its content type is in
+// DefaultLoggableContentTypes.
+type Options struct {
+	// MaxBodyBytes caps how many bytes of a request/response body are
+	// captured before truncation. Zero uses DefaultMaxBodyBytes; a negative
+	// value disables body capture entirely regardless of the other options.
+	MaxBodyBytes int64
+
+	// DisableRequestBody turns off request body capture. Request bodies are
+	// captured by default to preserve existing behaviour.
+	DisableRequestBody bool
+
+	// LogResponseBody turns on response body capture. Off by default since
+	// buffering the response is new behaviour and not every handler wants it.
+	LogResponseBody bool
+
+	// LoggableContentTypes lists the content types (matched by prefix) whose
+	// bodies are captured for logging. Nil uses DefaultLoggableContentTypes.
+	LoggableContentTypes []string
+
+	// RedactKeys lists JSON object keys whose values are replaced with
+	// "***" before a captured body is logged. Matching is case-insensitive.
+	// Nil uses DefaultRedactedKeys.
+	RedactKeys []string
+
+	// LogHeaders lists the request header names to include in the log,
+	// matched case-insensitively. Empty by default: no headers are logged.
+	LogHeaders []string
+
+	// RedactHeaders lists header names, among those in LogHeaders, whose
+	// values are replaced with "***" before logging.
+	RedactHeaders []string
+
+	// LogQuery includes the request's (redacted) query string in the log
+	// when true.
+	LogQuery bool
+
+	// RedactQueryParams lists URL query parameter names whose values are
+	// replaced with "***" before the query string is logged.
+	RedactQueryParams []string
+
+	// MaxTraceback caps how many stack frames are captured when a panic is
+	// recovered. Zero uses DefaultMaxTraceback.
+	MaxTraceback int
+
+	// TraceSkip adds extra frames to skip before capture begins, on top of
+	// the frames chilog itself contributes. Useful when Middleware's recover
+	// is reached through an extra layer of wrapping.
+	TraceSkip int
+
+	// PanicHandler, when set, is invoked with the recovered error and its
+	// captured frames so applications can forward panics to Sentry/OTel
+	// without re-parsing the log line.
+	PanicHandler func(ctx context.Context, err error, frames []Frame)
+
+	// ErrorWriter, when set, replaces WriteError's default RFC7807 encoder
+	// for both Handler and Middleware's panic recovery, so applications can
+	// plug in their own error body format.
+	ErrorWriter func(w http.ResponseWriter, r *http.Request, err error)
+
+	// Sampler, when set, decides whether a completed request is logged. It
+	// is bypassed for requests that exceed SlowRequestThreshold, which are
+	// always logged. A nil Sampler logs every request, matching historical
+	// behaviour.
+	Sampler Sampler
+
+	// SlowRequestThreshold, when positive, forces any request whose latency
+	// meets or exceeds it to be logged with an extra slow=true field,
+	// regardless of what Sampler would have decided. It is logged at Warn,
+	// or at Error when its status is >= 400 or the handler panicked.
+	SlowRequestThreshold time.Duration
+
+	// Tracer, when set, turns on OpenTelemetry span bridging: Middleware
+	// starts a server span covering the handler, links it to an inbound
+	// traceparent/tracestate or B3 header when present, and records the
+	// status code, error, and panic on it. trace_id and span_id are always
+	// added to the request's log context, even without a Tracer configured.
+	Tracer trace.Tracer
+}
+
+func (o Options) maxBodyBytes() int64 {
+	if o.MaxBodyBytes == 0 {
+		return DefaultMaxBodyBytes
+	}
+	return o.MaxBodyBytes
+}
+
+func (o Options) loggableContentTypes() []string {
+	if o.LoggableContentTypes != nil {
+		return o.LoggableContentTypes
+	}
+	return DefaultLoggableContentTypes
+}
+
+func (o Options) redactKeys() []string {
+	if o.RedactKeys != nil {
+		return o.RedactKeys
+	}
+	return DefaultRedactedKeys
+}
+
+func (o Options) maxTraceback() int {
+	if o.MaxTraceback == 0 {
+		return DefaultMaxTraceback
+	}
+	return o.MaxTraceback
+}
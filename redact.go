@@ -0,0 +1,143 @@
+package chilog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// isLoggableContentType reports whether contentType is covered by one of the
+// allowed prefixes. An empty contentType is treated as loggable so that
+// bodies sent without a Content-Type header (common in tests and simple
+// clients) are not silently dropped.
+func isLoggableContentType(contentType string, allowed []string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	mediaType := contentType
+	if idx := strings.IndexByte(mediaType, ';'); idx != -1 {
+		mediaType = mediaType[:idx]
+	}
+	mediaType = strings.TrimSpace(mediaType)
+
+	for _, a := range allowed {
+		if strings.HasPrefix(mediaType, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactJSON walks a decoded JSON value and replaces the values of any
+// object keys matching keys (case-insensitive) with "***".
+func redactJSON(v interface{}, keys []string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, inner := range val {
+			if matchesAny(k, keys) {
+				val[k] = "***"
+				continue
+			}
+			val[k] = redactJSON(inner, keys)
+		}
+		return val
+	case []interface{}:
+		for i, inner := range val {
+			val[i] = redactJSON(inner, keys)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+func matchesAny(s string, candidates []string) bool {
+	for _, c := range candidates {
+		if strings.EqualFold(s, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatBody compacts and redacts a JSON body, falling back to the raw
+// string for non-JSON payloads, then caps the result at max bytes. truncated
+// is tracked explicitly rather than inferred from the final length, since
+// data is chopped to max bytes up front and would otherwise never compare as
+// "too long" again.
+func formatBody(data []byte, keys []string, max int64) string {
+	truncated := int64(len(data)) > max
+	if truncated {
+		data = data[:max]
+	}
+
+	var js interface{}
+	if json.Unmarshal(data, &js) != nil {
+		return withTruncatedMarker(string(data), truncated)
+	}
+
+	js = redactJSON(js, keys)
+
+	redacted, err := json.Marshal(js)
+	if err != nil {
+		return withTruncatedMarker(string(data), truncated)
+	}
+
+	compacted := new(bytes.Buffer)
+	if err := json.Compact(compacted, redacted); err != nil {
+		return withTruncatedMarker(string(redacted), truncated)
+	}
+
+	return withTruncatedMarker(compacted.String(), truncated)
+}
+
+func withTruncatedMarker(s string, truncated bool) string {
+	if !truncated {
+		return s
+	}
+	return s + truncatedMarker
+}
+
+// redactQuery returns r.URL's query string with the named params masked.
+func redactQuery(values url.Values, keys []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	redacted := url.Values{}
+	for k, v := range values {
+		if matchesAny(k, keys) {
+			redacted[k] = []string{"***"}
+			continue
+		}
+		redacted[k] = v
+	}
+	return redacted.Encode()
+}
+
+// redactHeaders returns the values of the named headers, masking any whose
+// name matches redactKeys.
+func redactHeaders(h http.Header, names []string, redactKeys []string) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+
+	out := make(map[string]string, len(names))
+	for _, name := range names {
+		v := h.Get(name)
+		if v == "" {
+			continue
+		}
+		if matchesAny(name, redactKeys) {
+			v = "***"
+		}
+		out[name] = v
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,129 @@
+package chilog
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestIsLoggableContentType(t *testing.T) {
+	cases := []struct {
+		contentType string
+		want        bool
+	}{
+		{"", true},
+		{"application/json", true},
+		{"application/json; charset=utf-8", true},
+		{"text/plain", true},
+		{"multipart/form-data; boundary=x", false},
+		{"application/octet-stream", false},
+		{"image/png", false},
+	}
+
+	for _, c := range cases {
+		if got := isLoggableContentType(c.contentType, DefaultLoggableContentTypes); got != c.want {
+			t.Errorf("isLoggableContentType(%q) = %v, want %v", c.contentType, got, c.want)
+		}
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	input := map[string]interface{}{
+		"username": "alice",
+		"password": "hunter2",
+		"nested": map[string]interface{}{
+			"Token": "abc123",
+			"ok":    "fine",
+		},
+		"items": []interface{}{
+			map[string]interface{}{"secret": "x"},
+			"plain",
+		},
+	}
+
+	redactJSON(input, DefaultRedactedKeys)
+
+	if input["password"] != "***" {
+		t.Errorf("password = %v, want ***", input["password"])
+	}
+	if input["username"] != "alice" {
+		t.Errorf("username = %v, want alice (untouched)", input["username"])
+	}
+
+	nested := input["nested"].(map[string]interface{})
+	if nested["Token"] != "***" {
+		t.Errorf("nested.Token = %v, want *** (case-insensitive match)", nested["Token"])
+	}
+	if nested["ok"] != "fine" {
+		t.Errorf("nested.ok = %v, want fine (untouched)", nested["ok"])
+	}
+
+	items := input["items"].([]interface{})
+	itemMap := items[0].(map[string]interface{})
+	if itemMap["secret"] != "***" {
+		t.Errorf("items[0].secret = %v, want ***", itemMap["secret"])
+	}
+	if items[1] != "plain" {
+		t.Errorf("items[1] = %v, want plain (untouched)", items[1])
+	}
+}
+
+func TestFormatBody(t *testing.T) {
+	t.Run("redacts and compacts JSON", func(t *testing.T) {
+		got := formatBody([]byte(`{"password": "secret", "user": "bob"}`), DefaultRedactedKeys, DefaultMaxBodyBytes)
+		want := `{"password":"***","user":"bob"}`
+		if got != want {
+			t.Errorf("formatBody = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("passes through non-JSON bodies", func(t *testing.T) {
+		got := formatBody([]byte("not json"), DefaultRedactedKeys, DefaultMaxBodyBytes)
+		if got != "not json" {
+			t.Errorf("formatBody = %q, want %q", got, "not json")
+		}
+	})
+
+	t.Run("truncates over the cap with a marker", func(t *testing.T) {
+		got := formatBody([]byte("0123456789"), nil, 4)
+		want := "0123" + truncatedMarker
+		if got != want {
+			t.Errorf("formatBody = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestRedactQuery(t *testing.T) {
+	values := url.Values{"token": {"abc"}, "q": {"hello"}}
+	got := redactQuery(values, []string{"token"})
+
+	parsed, err := url.ParseQuery(got)
+	if err != nil {
+		t.Fatalf("redactQuery produced invalid query string %q: %v", got, err)
+	}
+	if parsed.Get("token") != "***" {
+		t.Errorf("token = %q, want ***", parsed.Get("token"))
+	}
+	if parsed.Get("q") != "hello" {
+		t.Errorf("q = %q, want hello (untouched)", parsed.Get("q"))
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Bearer abc")
+	h.Set("User-Agent", "curl/8.0")
+
+	got := redactHeaders(h, []string{"Authorization", "User-Agent"}, []string{"Authorization"})
+
+	if got["Authorization"] != "***" {
+		t.Errorf("Authorization = %q, want ***", got["Authorization"])
+	}
+	if got["User-Agent"] != "curl/8.0" {
+		t.Errorf("User-Agent = %q, want curl/8.0 (untouched)", got["User-Agent"])
+	}
+
+	if got := redactHeaders(h, nil, nil); got != nil {
+		t.Errorf("redactHeaders with no names = %v, want nil", got)
+	}
+}
@@ -0,0 +1,94 @@
+package chilog
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sampler decides whether a completed request should be logged. It is
+// consulted after the request finishes, so it sees the final status code
+// and latency, and is never asked about requests forced to log by
+// Options.SlowRequestThreshold.
+type Sampler interface {
+	Sample(r *http.Request, statusCode int, latency time.Duration) bool
+}
+
+// SamplerFunc adapts a plain function to the Sampler interface, the same way
+// http.HandlerFunc adapts a function to http.Handler.
+type SamplerFunc func(r *http.Request, statusCode int, latency time.Duration) bool
+
+// Sample implements Sampler.
+func (f SamplerFunc) Sample(r *http.Request, statusCode int, latency time.Duration) bool {
+	return f(r, statusCode, latency)
+}
+
+// NeverSample drops every request from the log. Combined with
+// Options.SlowRequestThreshold, this logs only slow requests and recovered
+// panics, which Middleware never passes to a Sampler.
+var NeverSample Sampler = SamplerFunc(func(r *http.Request, statusCode int, latency time.Duration) bool {
+	return false
+})
+
+// BasicSampler keeps 1 in every N requests. N of 0 or 1 keeps every request.
+type BasicSampler struct {
+	N uint32
+
+	count uint32
+}
+
+// Sample implements Sampler.
+func (s *BasicSampler) Sample(r *http.Request, statusCode int, latency time.Duration) bool {
+	if s.N <= 1 {
+		return true
+	}
+	return atomic.AddUint32(&s.count, 1)%s.N == 0
+}
+
+// BurstSampler is a token-bucket sampler: it allows up to Burst requests per
+// Period, then drops the rest until the bucket refills at the start of the
+// next period.
+type BurstSampler struct {
+	Burst  int
+	Period time.Duration
+
+	mu        sync.Mutex
+	tokens    int
+	periodEnd time.Time
+}
+
+// Sample implements Sampler.
+func (s *BurstSampler) Sample(r *http.Request, statusCode int, latency time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.After(s.periodEnd) {
+		s.tokens = s.Burst
+		s.periodEnd = now.Add(s.Period)
+	}
+
+	if s.tokens <= 0 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+// LevelSampler always keeps error responses (status >= 400) and delegates
+// sampling of everything else to Inner. A nil Inner keeps everything.
+type LevelSampler struct {
+	Inner Sampler
+}
+
+// Sample implements Sampler.
+func (s LevelSampler) Sample(r *http.Request, statusCode int, latency time.Duration) bool {
+	if statusCode >= 400 {
+		return true
+	}
+	if s.Inner == nil {
+		return true
+	}
+	return s.Inner.Sample(r, statusCode, latency)
+}
@@ -0,0 +1,89 @@
+package chilog
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSamplerInterfaceSatisfaction(t *testing.T) {
+	// These must compile as direct assignments, with no manual ".Sample"
+	// wrapping, to be usable the way the rest of Options is.
+	var samplers = []Sampler{
+		NeverSample,
+		&BasicSampler{N: 100},
+		&BurstSampler{Burst: 10, Period: time.Second},
+		LevelSampler{Inner: &BasicSampler{N: 2}},
+	}
+
+	for _, s := range samplers {
+		_ = s.Sample(&http.Request{}, http.StatusOK, 0)
+	}
+}
+
+func TestNeverSample(t *testing.T) {
+	if NeverSample.Sample(&http.Request{}, http.StatusOK, 0) {
+		t.Error("NeverSample.Sample() = true, want false")
+	}
+}
+
+func TestBasicSampler(t *testing.T) {
+	s := &BasicSampler{N: 3}
+	req := &http.Request{}
+
+	var kept int
+	for i := 0; i < 9; i++ {
+		if s.Sample(req, http.StatusOK, 0) {
+			kept++
+		}
+	}
+
+	if kept != 3 {
+		t.Errorf("kept %d of 9 requests with N=3, want 3", kept)
+	}
+}
+
+func TestBasicSamplerNOrOneKeepsEverything(t *testing.T) {
+	for _, n := range []uint32{0, 1} {
+		s := &BasicSampler{N: n}
+		for i := 0; i < 5; i++ {
+			if !s.Sample(&http.Request{}, http.StatusOK, 0) {
+				t.Errorf("BasicSampler{N: %d}.Sample() = false on call %d, want true", n, i)
+			}
+		}
+	}
+}
+
+func TestBurstSampler(t *testing.T) {
+	s := &BurstSampler{Burst: 2, Period: time.Hour}
+	req := &http.Request{}
+
+	if !s.Sample(req, http.StatusOK, 0) {
+		t.Error("1st request in burst should be kept")
+	}
+	if !s.Sample(req, http.StatusOK, 0) {
+		t.Error("2nd request in burst should be kept")
+	}
+	if s.Sample(req, http.StatusOK, 0) {
+		t.Error("3rd request should be dropped once the burst is exhausted")
+	}
+}
+
+func TestLevelSamplerAlwaysKeepsErrors(t *testing.T) {
+	s := LevelSampler{Inner: NeverSample}
+	req := &http.Request{}
+
+	if !s.Sample(req, http.StatusInternalServerError, 0) {
+		t.Error("LevelSampler should always keep status >= 400, even with a NeverSample inner")
+	}
+	if s.Sample(req, http.StatusOK, 0) {
+		t.Error("LevelSampler should defer 2xx to Inner")
+	}
+}
+
+func TestLevelSamplerNilInnerKeepsEverything(t *testing.T) {
+	s := LevelSampler{}
+	if !s.Sample(&http.Request{}, http.StatusOK, 0) {
+		t.Error("LevelSampler with nil Inner should keep everything")
+	}
+}
@@ -0,0 +1,56 @@
+package chilog
+
+import (
+	"runtime"
+	"strings"
+)
+
+// DefaultMaxTraceback is the maximum number of stack frames captured on
+// panic when Options.MaxTraceback is left at zero.
+const DefaultMaxTraceback = 32
+
+// packagePath is used to filter chilog's own frames out of captured traces.
+const packagePath = "github.com/Qiscus-Integration/chilog."
+
+// Frame describes a single stack frame captured during panic recovery.
+type Frame struct {
+	Func string
+	File string
+	Line int
+}
+
+// collectTrace walks the call stack of the goroutine that panicked, skipping
+// skip additional frames on top of runtime.Callers and collectTrace, and returns
+// up to max frames. Frames belonging to this package and the runtime's own
+// panic machinery are filtered out so the trace starts at the caller's code.
+func collectTrace(skip, max int) []Frame {
+	if max <= 0 {
+		return nil
+	}
+
+	pc := make([]uintptr, max+skip+16)
+	n := runtime.Callers(skip+2, pc)
+	if n == 0 {
+		return nil
+	}
+
+	callerFrames := runtime.CallersFrames(pc[:n])
+	frames := make([]Frame, 0, max)
+	for {
+		f, more := callerFrames.Next()
+		if !isInternalFrame(f) {
+			frames = append(frames, Frame{Func: f.Function, File: f.File, Line: f.Line})
+			if len(frames) >= max {
+				break
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	return frames
+}
+
+func isInternalFrame(f runtime.Frame) bool {
+	return strings.HasPrefix(f.Function, packagePath) || strings.HasPrefix(f.Function, "runtime.")
+}
@@ -0,0 +1,58 @@
+package chilog
+
+import (
+	"runtime"
+	"testing"
+)
+
+func deeplyNestedCaller() []Frame {
+	return collectTrace(0, DefaultMaxTraceback)
+}
+
+func TestCollectTrace(t *testing.T) {
+	frames := deeplyNestedCaller()
+
+	if len(frames) == 0 {
+		t.Fatal("collectTrace returned no frames")
+	}
+
+	for _, f := range frames {
+		if isInternalFrame(runtime.Frame{Function: f.Func}) {
+			t.Errorf("frame %q should have been filtered out as internal", f.Func)
+		}
+	}
+
+	if frames[0].Func == "" || frames[0].File == "" || frames[0].Line == 0 {
+		t.Errorf("frames[0] = %+v, want populated Func/File/Line", frames[0])
+	}
+}
+
+func TestCollectTraceRespectsMax(t *testing.T) {
+	frames := collectTrace(0, 1)
+	if len(frames) > 1 {
+		t.Errorf("collectTrace(0, 1) returned %d frames, want at most 1", len(frames))
+	}
+}
+
+func TestCollectTraceZeroMax(t *testing.T) {
+	if frames := collectTrace(0, 0); frames != nil {
+		t.Errorf("collectTrace(0, 0) = %v, want nil", frames)
+	}
+}
+
+func TestIsInternalFrame(t *testing.T) {
+	cases := []struct {
+		fn   string
+		want bool
+	}{
+		{"github.com/Qiscus-Integration/chilog.collectTrace", true},
+		{"runtime.gopanic", true},
+		{"github.com/example/app.Handler", false},
+	}
+
+	for _, c := range cases {
+		if got := isInternalFrame(runtime.Frame{Function: c.fn}); got != c.want {
+			t.Errorf("isInternalFrame(%q) = %v, want %v", c.fn, got, c.want)
+		}
+	}
+}
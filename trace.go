@@ -0,0 +1,130 @@
+package chilog
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// W3C Trace Context and B3 header names.
+const (
+	traceParentHeader = "traceparent"
+	traceStateHeader  = "tracestate"
+	b3Header          = "b3"
+	b3TraceIDHeader   = "X-B3-TraceId"
+	b3SpanIDHeader    = "X-B3-SpanId"
+)
+
+// propagatedTrace holds the trace/span IDs to log, and the remote span
+// context to link a new span to, for a single request.
+type propagatedTrace struct {
+	TraceID string
+	SpanID  string
+	Remote  trace.SpanContext
+}
+
+// extractTrace parses the incoming traceparent/tracestate headers, falling
+// back to B3, and generates a fresh trace ID when neither is present so logs
+// from the very first hop are still joinable with later spans.
+func extractTrace(r *http.Request) propagatedTrace {
+	if sc, ok := parseTraceParent(r.Header.Get(traceParentHeader), r.Header.Get(traceStateHeader)); ok {
+		return propagatedTrace{TraceID: sc.TraceID().String(), SpanID: sc.SpanID().String(), Remote: sc}
+	}
+
+	if sc, ok := parseB3(r.Header); ok {
+		return propagatedTrace{TraceID: sc.TraceID().String(), SpanID: sc.SpanID().String(), Remote: sc}
+	}
+
+	var id trace.TraceID
+	_, _ = rand.Read(id[:])
+	return propagatedTrace{TraceID: id.String()}
+}
+
+// parseTraceParent parses a "traceparent: version-traceid-spanid-flags"
+// header as defined by the W3C Trace Context spec.
+func parseTraceParent(tp, ts string) (trace.SpanContext, bool) {
+	parts := strings.Split(tp, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return trace.SpanContext{}, false
+	}
+
+	traceID, err := trace.TraceIDFromHex(parts[1])
+	if err != nil || !traceID.IsValid() {
+		return trace.SpanContext{}, false
+	}
+
+	spanID, err := trace.SpanIDFromHex(parts[2])
+	if err != nil || !spanID.IsValid() {
+		return trace.SpanContext{}, false
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return trace.SpanContext{}, false
+	}
+
+	cfg := trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.TraceFlags(flags[0]),
+		Remote:     true,
+	}
+
+	if state, err := trace.ParseTraceState(ts); err == nil {
+		cfg.TraceState = state
+	}
+
+	return trace.NewSpanContext(cfg), true
+}
+
+// parseB3 parses either the single "b3" header or the X-B3-TraceId/
+// X-B3-SpanId pair.
+func parseB3(h http.Header) (trace.SpanContext, bool) {
+	if b3 := h.Get(b3Header); b3 != "" {
+		parts := strings.Split(b3, "-")
+		if len(parts) < 2 {
+			return trace.SpanContext{}, false
+		}
+		return spanContextFromHex(parts[0], parts[1])
+	}
+
+	return spanContextFromHex(h.Get(b3TraceIDHeader), h.Get(b3SpanIDHeader))
+}
+
+func spanContextFromHex(traceIDHex, spanIDHex string) (trace.SpanContext, bool) {
+	if traceIDHex == "" || spanIDHex == "" {
+		return trace.SpanContext{}, false
+	}
+
+	// B3 allows a 64-bit (16 hex char) trace ID; left-pad it to 128 bits.
+	if len(traceIDHex) == 16 {
+		traceIDHex = strings.Repeat("0", 16) + traceIDHex
+	}
+
+	traceID, err := trace.TraceIDFromHex(traceIDHex)
+	if err != nil || !traceID.IsValid() {
+		return trace.SpanContext{}, false
+	}
+
+	spanID, err := trace.SpanIDFromHex(spanIDHex)
+	if err != nil || !spanID.IsValid() {
+		return trace.SpanContext{}, false
+	}
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Remote:  true,
+	}), true
+}
+
+// httpStatusAttribute is split out so it can be reused by both the normal
+// completion path and the panic recovery path.
+func httpStatusAttribute(statusCode int) attribute.KeyValue {
+	return semconv.HTTPStatusCodeKey.Int(statusCode)
+}
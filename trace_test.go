@@ -0,0 +1,103 @@
+package chilog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTraceParentValid(t *testing.T) {
+	tp := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc, ok := parseTraceParent(tp, "")
+	if !ok {
+		t.Fatal("parseTraceParent() ok = false, want true")
+	}
+	if got := sc.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("TraceID = %q", got)
+	}
+	if got := sc.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("SpanID = %q", got)
+	}
+	if !sc.IsRemote() {
+		t.Error("span context from an inbound header should be marked remote")
+	}
+}
+
+func TestParseTraceParentInvalid(t *testing.T) {
+	cases := []string{
+		"",
+		"not-a-traceparent",
+		"00-tooshort-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",    // missing flags segment
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01", // all-zero trace id is invalid
+	}
+
+	for _, tp := range cases {
+		if _, ok := parseTraceParent(tp, ""); ok {
+			t.Errorf("parseTraceParent(%q) ok = true, want false", tp)
+		}
+	}
+}
+
+func TestParseB3SingleHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set(b3Header, "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1")
+
+	sc, ok := parseB3(h)
+	if !ok {
+		t.Fatal("parseB3() ok = false, want true")
+	}
+	if got := sc.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("TraceID = %q", got)
+	}
+}
+
+func TestParseB3MultiHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set(b3TraceIDHeader, "a3ce929d0e0e4736") // 64-bit, needs left-padding
+	h.Set(b3SpanIDHeader, "00f067aa0ba902b7")
+
+	sc, ok := parseB3(h)
+	if !ok {
+		t.Fatal("parseB3() ok = false, want true")
+	}
+	// A 64-bit B3 trace ID is left-padded with 16 hex zeros to 128 bits.
+	want := "0000000000000000a3ce929d0e0e4736"
+	if got := sc.TraceID().String(); got != want {
+		t.Errorf("TraceID = %q, want %q", got, want)
+	}
+}
+
+func TestParseB3Missing(t *testing.T) {
+	if _, ok := parseB3(http.Header{}); ok {
+		t.Error("parseB3(empty headers) ok = true, want false")
+	}
+}
+
+func TestExtractTraceGeneratesFreshID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	tr := extractTrace(r)
+	if tr.TraceID == "" {
+		t.Fatal("extractTrace should generate a trace ID when no header is present")
+	}
+	if len(tr.TraceID) != 32 {
+		t.Errorf("generated TraceID %q length = %d, want 32", tr.TraceID, len(tr.TraceID))
+	}
+	if tr.Remote.IsValid() {
+		t.Error("Remote span context should be invalid when nothing was propagated")
+	}
+}
+
+func TestExtractTracePrefersTraceParentOverB3(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(traceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Set(b3TraceIDHeader, "ffffffffffffffffffffffffffffffff")
+	r.Header.Set(b3SpanIDHeader, "ffffffffffffffff")
+
+	tr := extractTrace(r)
+	if tr.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("TraceID = %q, want the traceparent's trace id", tr.TraceID)
+	}
+}